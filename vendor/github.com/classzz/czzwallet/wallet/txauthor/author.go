@@ -7,6 +7,7 @@ package txauthor
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/classzz/classzz/chaincfg"
 	"github.com/classzz/classzz/txscript"
@@ -57,6 +58,115 @@ type AuthoredTx struct {
 // ChangeSource provides P2PKH change output scripts for transaction creation.
 type ChangeSource func() ([]byte, error)
 
+// Credit describes a spendable unspent transaction output considered by a
+// CoinSelectionStrategy when building an InputSource.
+type Credit struct {
+	OutPoint wire.OutPoint
+	PkScript []byte
+	Amount   czzutil.Amount
+}
+
+// CoinSelectionStrategy identifies an algorithm for choosing which unspent
+// outputs to spend from a set of eligible Credits.
+type CoinSelectionStrategy int
+
+const (
+	// CoinSelectionLargest selects the largest eligible outputs first.
+	CoinSelectionLargest CoinSelectionStrategy = iota
+
+	// CoinSelectionRandom selects eligible outputs in random order,
+	// accumulating one at a time so unrelated coins end up combined in
+	// the same transaction.  This reduces the wallet fingerprinting
+	// possible when the largest-first heuristic is used.
+	CoinSelectionRandom
+
+	// CoinSelectionSmallest selects the smallest eligible outputs first.
+	CoinSelectionSmallest
+
+	// CoinSelectionBranchAndBound names Branch-and-Bound selection of an
+	// exact-match input set that avoids a change output.  MakeInputSource
+	// has no fee rate to search with and selects largest-first for it;
+	// the search itself is done by NewUnsignedTransactionBnB.
+	CoinSelectionBranchAndBound
+)
+
+// MakeInputSource returns an InputSource that selects from credits according
+// to strategy.  The returned InputSource may be called multiple times with
+// increasing targets, as done by NewUnsignedTransaction, and always
+// considers the full credits slice rather than remembering previous calls.
+func MakeInputSource(credits []Credit, strategy CoinSelectionStrategy) InputSource {
+	switch strategy {
+	case CoinSelectionSmallest:
+		return makeSortedInputSource(credits, func(i, j int) bool {
+			return credits[i].Amount < credits[j].Amount
+		})
+	case CoinSelectionRandom:
+		return makeShuffledInputSource(credits)
+	case CoinSelectionBranchAndBound:
+		// MakeInputSource has no fee-rate context with which to run a
+		// true Branch-and-Bound search (see selectCoinsBnB, used by
+		// NewUnsignedTransactionBnB), so fall back to the
+		// largest-first heuristic here.
+		return makeSortedInputSource(credits, func(i, j int) bool {
+			return credits[i].Amount > credits[j].Amount
+		})
+	case CoinSelectionLargest:
+		fallthrough
+	default:
+		return makeSortedInputSource(credits, func(i, j int) bool {
+			return credits[i].Amount > credits[j].Amount
+		})
+	}
+}
+
+// makeSortedInputSource orders a copy of credits using less and accumulates
+// them one at a time until the target amount is reached.  less compares
+// credits by their indices in credits.
+func makeSortedInputSource(credits []Credit, less func(i, j int) bool) InputSource {
+	idx := make([]int, len(credits))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return less(idx[a], idx[b]) })
+	ordered := make([]Credit, len(credits))
+	for k, i := range idx {
+		ordered[k] = credits[i]
+	}
+	return accumulatingInputSource(ordered)
+}
+
+// makeShuffledInputSource orders a copy of credits randomly and accumulates
+// them one at a time until the target amount is reached.
+func makeShuffledInputSource(credits []Credit) InputSource {
+	shuffled := make([]Credit, len(credits))
+	copy(shuffled, credits)
+	cprng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return accumulatingInputSource(shuffled)
+}
+
+// accumulatingInputSource returns an InputSource that walks ordered in order,
+// adding each credit as an input until the requested target is met.
+func accumulatingInputSource(ordered []Credit) InputSource {
+	return func(target czzutil.Amount) (czzutil.Amount, []*wire.TxIn, []czzutil.Amount, [][]byte, error) {
+		var total czzutil.Amount
+		var inputs []*wire.TxIn
+		var inputValues []czzutil.Amount
+		var scripts [][]byte
+		for _, credit := range ordered {
+			if total >= target {
+				break
+			}
+			total += credit.Amount
+			inputs = append(inputs, wire.NewTxIn(&credit.OutPoint, nil, nil))
+			inputValues = append(inputValues, credit.Amount)
+			scripts = append(scripts, credit.PkScript)
+		}
+		return total, inputs, inputValues, scripts, nil
+	}
+}
+
 // NewUnsignedTransaction creates an unsigned transaction paying to one or more
 // non-change outputs.  An appropriate transaction fee is included based on the
 // transaction size.
@@ -135,6 +245,398 @@ func NewUnsignedTransaction(outputs []*wire.TxOut, relayFeePerKb czzutil.Amount,
 	}
 }
 
+// bnbMaxTries bounds the number of nodes visited by selectCoinsBnB so that a
+// pathological input set cannot make coin selection run unbounded.
+const bnbMaxTries = 100000
+
+// selectCoinsBnB performs a depth-first Branch-and-Bound search over credits,
+// sorted by descending effective value (amount minus the fee to spend that
+// input), looking for a subset whose effective value sums to within
+// [target, target+costOfChange]. inputFee is the fee contributed by a single
+// additional input at the transaction's fee rate.
+//
+// At each node the search either includes or excludes the current credit; it
+// prunes a branch once the running sum exceeds target+costOfChange, once the
+// remaining credits cannot possibly reach target, or once bnbMaxTries nodes
+// have been visited. The boolean result reports whether a matching subset was
+// found.
+func selectCoinsBnB(credits []Credit, target, costOfChange, inputFee czzutil.Amount) ([]Credit, bool) {
+	ordered := make([]Credit, len(credits))
+	copy(ordered, credits)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Amount > ordered[j].Amount
+	})
+
+	effValue := func(c Credit) czzutil.Amount {
+		v := c.Amount - inputFee
+		if v < 0 {
+			v = 0
+		}
+		return v
+	}
+
+	// remaining[i] is the sum of effective values of ordered[i:].
+	remaining := make([]czzutil.Amount, len(ordered)+1)
+	for i := len(ordered) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + effValue(ordered[i])
+	}
+
+	var best []Credit
+	selected := make([]Credit, 0, len(ordered))
+	tries := 0
+
+	var search func(i int, sum czzutil.Amount) bool
+	search = func(i int, sum czzutil.Amount) bool {
+		tries++
+		switch {
+		case tries > bnbMaxTries:
+			return false
+		case sum > target+costOfChange:
+			return false
+		case sum >= target:
+			best = append(best[:0], selected...)
+			return true
+		case i >= len(ordered):
+			return false
+		case sum+remaining[i] < target:
+			return false
+		}
+
+		// Include ordered[i].
+		selected = append(selected, ordered[i])
+		if search(i+1, sum+effValue(ordered[i])) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		// Exclude ordered[i].
+		return search(i+1, sum)
+	}
+
+	if search(0, 0) {
+		return best, true
+	}
+	return nil, false
+}
+
+// NewUnsignedTransactionBnB creates an unsigned transaction like
+// NewUnsignedTransaction, but first attempts Branch-and-Bound coin selection
+// (see selectCoinsBnB) over credits to find an input set whose value exactly
+// covers the outputs and fee, producing a changeless transaction with the
+// excess, if any, assigned to the fee. If no such set is found, it falls back
+// to NewUnsignedTransaction using the largest-first InputSource built from
+// credits.
+func NewUnsignedTransactionBnB(outputs []*wire.TxOut, relayFeePerKb czzutil.Amount,
+	credits []Credit, fetchChange ChangeSource) (*AuthoredTx, error) {
+
+	targetAmount := h.SumOutputValues(outputs)
+
+	noInputsSize := txsizes.EstimateSerializeSize(0, outputs, true)
+	oneInputSize := txsizes.EstimateSerializeSize(1, outputs, true)
+	inputFee := txrules.FeeForSerializeSize(relayFeePerKb, oneInputSize) -
+		txrules.FeeForSerializeSize(relayFeePerKb, noInputsSize)
+
+	noChangeSize := txsizes.EstimateSerializeSize(1, outputs, false)
+	withChangeSize := txsizes.EstimateSerializeSize(1, outputs, true)
+	costToCreateChange := txrules.FeeForSerializeSize(relayFeePerKb, withChangeSize) -
+		txrules.FeeForSerializeSize(relayFeePerKb, noChangeSize)
+	costOfChange := costToCreateChange + inputFee
+
+	// The BnB path never produces a change output, so the base fee used
+	// to size target must not assume one either.
+	noChangeNoInputsSize := txsizes.EstimateSerializeSize(0, outputs, false)
+	baseFee := txrules.FeeForSerializeSize(relayFeePerKb, noChangeNoInputsSize)
+	target := targetAmount + baseFee
+
+	if selected, ok := selectCoinsBnB(credits, target, costOfChange, inputFee); ok {
+		var total czzutil.Amount
+		inputs := make([]*wire.TxIn, 0, len(selected))
+		inputValues := make([]czzutil.Amount, 0, len(selected))
+		scripts := make([][]byte, 0, len(selected))
+		for _, credit := range selected {
+			total += credit.Amount
+			inputs = append(inputs, wire.NewTxIn(&credit.OutPoint, nil, nil))
+			inputValues = append(inputValues, credit.Amount)
+			scripts = append(scripts, credit.PkScript)
+		}
+
+		unsignedTransaction := &wire.MsgTx{
+			Version:  wire.TxVersion,
+			TxIn:     inputs,
+			TxOut:    outputs,
+			LockTime: 0,
+		}
+
+		return &AuthoredTx{
+			Tx:              unsignedTransaction,
+			PrevScripts:     scripts,
+			PrevInputValues: inputValues,
+			TotalInput:      total,
+			ChangeIndex:     -1,
+		}, nil
+	}
+
+	return NewUnsignedTransaction(outputs, relayFeePerKb,
+		MakeInputSource(credits, CoinSelectionLargest), fetchChange)
+}
+
+// bip125MaxReplaceableSequence is the highest input sequence number that
+// still signals replaceability per BIP-125 ("sequence number less than
+// (0xffffffff-1)").
+const bip125MaxReplaceableSequence = wire.MaxTxInSequenceNum - 2
+
+// minRelayFeePerKb approximates BIP-125 rule 4's "minimum relay fee
+// (currently 1000 satoshis per kB)" floor on how much more a replacement
+// must pay for its own bandwidth, independent of the fee rate the caller is
+// targeting with newFeeRate.
+const minRelayFeePerKb = czzutil.Amount(1000)
+
+// BumpFee creates a replacement for authored that pays newFeeRate, following
+// the rules of BIP-125 opt-in full-replace-by-fee: the replacement reuses
+// every input of the original transaction, marks all of them replaceable
+// (sequence < 0xfffffffe), and pays an absolute fee of at least the
+// original's fee plus the minimum relay bump, or the replacement's fee at
+// newFeeRate, whichever is greater. The original's change output is reduced
+// to absorb the increased fee before fetchInputs is consulted for
+// additional inputs; fetchChange is only called if the replacement ends up
+// needing a change output the original did not have.
+//
+// BUGS: the minimum relay bump uses the hard-coded 1000 sat/kB default
+// relay fee rather than tracking the node's live minimum relay fee.
+func BumpFee(authored *AuthoredTx, newFeeRate czzutil.Amount, fetchInputs InputSource,
+	fetchChange ChangeSource) (*AuthoredTx, error) {
+
+	if len(authored.Tx.TxIn) == 0 {
+		return nil, errors.New("original transaction has no inputs to reuse")
+	}
+
+	outputs := append([]*wire.TxOut(nil), authored.Tx.TxOut...)
+	var changeScript []byte
+	var changeAmount czzutil.Amount
+	if authored.ChangeIndex >= 0 {
+		changeOut := outputs[authored.ChangeIndex]
+		changeScript = changeOut.PkScript
+		changeAmount = czzutil.Amount(changeOut.Value)
+		l := len(outputs) - 1
+		outputs[authored.ChangeIndex] = outputs[l]
+		outputs = outputs[:l:l]
+	}
+	targetAmount := h.SumOutputValues(outputs)
+
+	originalFee := authored.TotalInput - targetAmount - changeAmount
+	if originalFee < 0 {
+		return nil, errors.New("original transaction pays a negative fee")
+	}
+
+	inputs := append([]*wire.TxIn(nil), authored.Tx.TxIn...)
+	inputValues := append([]czzutil.Amount(nil), authored.PrevInputValues...)
+	scripts := append([][]byte(nil), authored.PrevScripts...)
+	inputAmount := authored.TotalInput
+
+	for {
+		estimatedSize := txsizes.EstimateSerializeSize(len(inputs), outputs, true)
+		minRelayBump := txrules.FeeForSerializeSize(minRelayFeePerKb, estimatedSize)
+		requiredFee := originalFee + minRelayBump
+		if feeAtNewRate := txrules.FeeForSerializeSize(newFeeRate, estimatedSize); feeAtNewRate > requiredFee {
+			requiredFee = feeAtNewRate
+		}
+
+		available := inputAmount - targetAmount
+		if available < requiredFee {
+			more, moreInputs, moreValues, moreScripts, err := fetchInputs(requiredFee - available)
+			if err != nil {
+				return nil, err
+			}
+			if more < requiredFee-available {
+				return nil, insufficientFundsError{}
+			}
+			inputs = append(inputs, moreInputs...)
+			inputValues = append(inputValues, moreValues...)
+			scripts = append(scripts, moreScripts...)
+			inputAmount += more
+			continue
+		}
+
+		for _, in := range inputs {
+			in.Sequence = bip125MaxReplaceableSequence
+		}
+
+		unsignedTransaction := &wire.MsgTx{
+			Version:  wire.TxVersion,
+			TxIn:     inputs,
+			TxOut:    outputs,
+			LockTime: 0,
+		}
+		changeIndex := -1
+		remaining := inputAmount - targetAmount - requiredFee
+		if remaining != 0 && !txrules.IsDustAmount(remaining,
+			txsizes.P2PKHPkScriptSize, newFeeRate) {
+
+			if changeScript == nil {
+				var err error
+				changeScript, err = fetchChange()
+				if err != nil {
+					return nil, err
+				}
+			}
+			change := wire.NewTxOut(int64(remaining), changeScript)
+			l := len(outputs)
+			unsignedTransaction.TxOut = append(outputs[:l:l], change)
+			changeIndex = l
+		}
+
+		return &AuthoredTx{
+			Tx:              unsignedTransaction,
+			PrevScripts:     scripts,
+			PrevInputValues: inputValues,
+			TotalInput:      inputAmount,
+			ChangeIndex:     changeIndex,
+		}, nil
+	}
+}
+
+// NewChildPaysForParent authors a standalone transaction that spends only
+// parent's change output to destScript, paying a fee high enough that
+// parent and child together reach packageFeeRate given that parent already
+// pays parentFee. The child produces no change; any value beyond the
+// required child fee is paid to destScript.
+func NewChildPaysForParent(parent *AuthoredTx, parentFee, packageFeeRate czzutil.Amount,
+	destScript []byte) (*AuthoredTx, error) {
+
+	if parent.ChangeIndex < 0 {
+		return nil, errors.New("parent transaction has no change output to spend")
+	}
+
+	changeOut := parent.Tx.TxOut[parent.ChangeIndex]
+	changeAmount := czzutil.Amount(changeOut.Value)
+	outpoint := wire.OutPoint{Hash: parent.Tx.TxHash(), Index: uint32(parent.ChangeIndex)}
+
+	outputs := []*wire.TxOut{wire.NewTxOut(0, destScript)}
+	parentSize := txsizes.EstimateSerializeSize(len(parent.Tx.TxIn), parent.Tx.TxOut, false)
+	childSize := txsizes.EstimateSerializeSize(1, outputs, false)
+	packageFee := txrules.FeeForSerializeSize(packageFeeRate, parentSize+childSize)
+	if packageFee <= parentFee {
+		return nil, errors.New("parent already pays at least the target package fee rate")
+	}
+	childFee := packageFee - parentFee
+	if changeAmount <= childFee {
+		return nil, insufficientFundsError{}
+	}
+	outputs[0].Value = int64(changeAmount - childFee)
+
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     []*wire.TxIn{wire.NewTxIn(&outpoint, nil, nil)},
+		TxOut:    outputs,
+		LockTime: 0,
+	}
+
+	return &AuthoredTx{
+		Tx:              unsignedTransaction,
+		PrevScripts:     [][]byte{changeOut.PkScript},
+		PrevInputValues: []czzutil.Amount{changeAmount},
+		TotalInput:      changeAmount,
+		ChangeIndex:     -1,
+	}, nil
+}
+
+// InputSweepSource provides every spendable transaction input that a sweep
+// transaction should include. Unlike InputSource, it is not called
+// repeatedly with increasing targets: a sweep always consumes everything the
+// source returns.
+type InputSweepSource func() (inputs []*wire.TxIn, inputValues []czzutil.Amount,
+	scripts [][]byte, err error)
+
+// NewUnsignedSweepTransaction creates an unsigned transaction that spends
+// every input returned by fetchAllInputs to outputs, producing no change
+// output. The fee is computed from the signed-size estimate for the swept
+// input set, and the excess input value left over after paying outputs and
+// fee is added on top of feeSourceIndex's output value if feeSourceIndex is
+// non-negative (an index past the end of outputs is an error), or
+// distributed proportionally across all outputs by value if feeSourceIndex
+// is negative.
+//
+// The value already set on each output is kept and the excess is added on
+// top of it, so the outputs together always pay the total input value minus
+// the fee.  The inputs must cover the outputs' values plus the fee, otherwise
+// an InputSourceError is returned.
+//
+// NewUnsignedTransaction always sizes its inputs to a target amount, which
+// makes it awkward for wallet-sweeping flows that want to drain an account
+// entirely; NewUnsignedSweepTransaction is the dedicated entry point for
+// that instead.
+func NewUnsignedSweepTransaction(outputs []*wire.TxOut, relayFeePerKb czzutil.Amount,
+	feeSourceIndex int, fetchAllInputs InputSweepSource) (*AuthoredTx, error) {
+
+	inputs, inputValues, scripts, err := fetchAllInputs()
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, errors.New("no inputs available to sweep")
+	}
+
+	var inputAmount czzutil.Amount
+	for _, v := range inputValues {
+		inputAmount += v
+	}
+
+	estimatedSize := txsizes.EstimateSerializeSize(len(inputs), outputs, false)
+	fee := txrules.FeeForSerializeSize(relayFeePerKb, estimatedSize)
+
+	targetAmount := h.SumOutputValues(outputs)
+	if inputAmount < targetAmount+fee {
+		return nil, insufficientFundsError{}
+	}
+	excess := inputAmount - targetAmount - fee
+
+	sweptOutputs := make([]*wire.TxOut, len(outputs))
+	for i, out := range outputs {
+		sweptOutputs[i] = wire.NewTxOut(out.Value, out.PkScript)
+	}
+
+	// excess already has the fee netted out (inputAmount - targetAmount -
+	// fee), so distributing it across sweptOutputs is the only
+	// adjustment needed; the fee must not be subtracted a second time.
+	if feeSourceIndex >= 0 {
+		if feeSourceIndex >= len(sweptOutputs) {
+			return nil, errors.New("fee source index out of range")
+		}
+		sweptOutputs[feeSourceIndex].Value += int64(excess)
+	} else {
+		remainingExcess := excess
+		for i, out := range sweptOutputs {
+			share := remainingExcess
+			if i < len(sweptOutputs)-1 {
+				share = excess * czzutil.Amount(out.Value) / targetAmount
+				remainingExcess -= share
+			}
+			out.Value += int64(share)
+		}
+	}
+
+	for _, out := range sweptOutputs {
+		if out.Value < 0 {
+			return nil, errors.New("fee exceeds value of outputs being swept")
+		}
+	}
+
+	unsignedTransaction := &wire.MsgTx{
+		Version:  wire.TxVersion,
+		TxIn:     inputs,
+		TxOut:    sweptOutputs,
+		LockTime: 0,
+	}
+
+	return &AuthoredTx{
+		Tx:              unsignedTransaction,
+		PrevScripts:     scripts,
+		PrevInputValues: inputValues,
+		TotalInput:      inputAmount,
+		ChangeIndex:     -1,
+	}, nil
+}
+
 // RandomizeOutputPosition randomizes the position of a transaction's output by
 // swapping it with a random output.  The new index is returned.  This should be
 // done before signing.
@@ -171,6 +673,16 @@ type SecretsSource interface {
 // Previous output scripts being redeemed by each input are passed in prevPkScripts
 // and the slice length must match the number of inputs.  Private keys and redeem
 // scripts are looked up using a SecretsSource based on the previous output script.
+//
+// The previous output script's type determines what's produced: legacy
+// P2PKH and bare P2SH (other than nested P2WPKH) inputs get a
+// SignatureScript, and P2WPKH and P2SH-P2WPKH inputs get a BIP-143 witness.
+// inputValues supplies the previous output amounts the segwit sighash
+// algorithm requires.
+//
+// BUGS: classzz predates BIP-341 Taproot, so neither txscript nor this
+// function can sign P2TR inputs; AddAllInputScripts returns an error for any
+// witness program other than P2WPKH rather than attempting one.
 func AddAllInputScripts(tx *wire.MsgTx, prevPkScripts [][]byte, inputValues []czzutil.Amount,
 	secrets SecretsSource) error {
 
@@ -181,31 +693,132 @@ func AddAllInputScripts(tx *wire.MsgTx, prevPkScripts [][]byte, inputValues []cz
 		return errors.New("tx.TxIn and prevPkScripts slices must " +
 			"have equal length")
 	}
+	if len(inputs) != len(inputValues) {
+		return errors.New("tx.TxIn and inputValues slices must " +
+			"have equal length")
+	}
+
+	sigHashes := txscript.NewTxSigHashes(tx)
 
 	for i := range inputs {
 		pkScript := prevPkScripts[i]
-		// tx, idx, amt, subscript, hashtype, pk, compress
-		// First obtain the key pair associated with this p2wkh address.
-		_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript,
-			chainParams)
-		if err != nil {
-			return err
-		}
-		privKey, compressed, err := secrets.GetKey(addrs[0])
-		if err != nil {
-			return err
-		}
-		script, err := txscript.SignatureScript(tx, i, int64(inputValues[i].ToUnit(czzutil.AmountSatoshi)),
-			pkScript, txscript.SigHashAll, privKey, compressed)
-		if err != nil {
-			return err
+		amount := int64(inputValues[i].ToUnit(czzutil.AmountSatoshi))
+
+		switch {
+		case txscript.IsWitnessProgram(pkScript) && !txscript.IsPayToWitnessPubKeyHash(pkScript):
+			return errors.New("unsupported witness program: only P2WPKH " +
+				"witness inputs are supported")
+
+		case txscript.IsPayToWitnessPubKeyHash(pkScript):
+			witness, err := p2wkhWitness(tx, sigHashes, i, amount, pkScript, secrets)
+			if err != nil {
+				return err
+			}
+			inputs[i].Witness = witness
+
+		case txscript.IsPayToScriptHash(pkScript):
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, chainParams)
+			if err != nil {
+				return err
+			}
+			redeemScript, err := secrets.GetScript(addrs[0])
+			if err != nil {
+				return err
+			}
+			if txscript.IsPayToWitnessPubKeyHash(redeemScript) {
+				witness, err := p2wkhWitness(tx, sigHashes, i, amount, redeemScript, secrets)
+				if err != nil {
+					return err
+				}
+				sigScript, err := txscript.NewScriptBuilder().
+					AddData(redeemScript).Script()
+				if err != nil {
+					return err
+				}
+				inputs[i].Witness = witness
+				inputs[i].SignatureScript = sigScript
+				continue
+			}
+
+			privKey, compressed, err := secrets.GetKey(addrs[0])
+			if err != nil {
+				return err
+			}
+			script, err := txscript.SignatureScript(tx, i, amount,
+				pkScript, txscript.SigHashAll, privKey, compressed)
+			if err != nil {
+				return err
+			}
+			inputs[i].SignatureScript = script
+
+		default:
+			// First obtain the key pair associated with this p2pkh address.
+			_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, chainParams)
+			if err != nil {
+				return err
+			}
+			privKey, compressed, err := secrets.GetKey(addrs[0])
+			if err != nil {
+				return err
+			}
+			script, err := txscript.SignatureScript(tx, i, amount,
+				pkScript, txscript.SigHashAll, privKey, compressed)
+			if err != nil {
+				return err
+			}
+			inputs[i].SignatureScript = script
 		}
-		inputs[i].SignatureScript = script
 	}
 
 	return nil
 }
 
+// P2WPKHSigScript returns the classic P2PKH script
+// (OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG) redeeming the pubkey
+// hash committed to by a P2WPKH witness program, whether native or nested
+// inside a P2SH-P2WPKH scriptPubKey. BIP-143 requires this script, not the
+// OP_0 <hash> witness program itself, as the scriptCode input to the
+// sighash algorithm for such inputs.
+func P2WPKHSigScript(witnessProgram []byte, chainParams *chaincfg.Params) ([]byte, error) {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(witnessProgram, chainParams)
+	if err != nil {
+		return nil, err
+	}
+	witnessAddr, ok := addrs[0].(*czzutil.AddressWitnessPubKeyHash)
+	if !ok {
+		return nil, errors.New("witness program does not redeem to a " +
+			"P2WPKH address")
+	}
+	p2pkhAddr, err := czzutil.NewAddressPubKeyHash(witnessAddr.Hash160()[:], chainParams)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(p2pkhAddr)
+}
+
+// p2wkhWitness produces the BIP-143 witness for a P2WPKH input, whether
+// spent natively or nested inside a P2SH-P2WPKH scriptPubKey. witnessProgram
+// is the inner witness program: pkScript itself for a native P2WPKH input,
+// or the redeem script for a nested one.
+func p2wkhWitness(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes, idx int, amount int64,
+	witnessProgram []byte, secrets SecretsSource) (wire.TxWitness, error) {
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(witnessProgram, secrets.ChainParams())
+	if err != nil {
+		return nil, err
+	}
+	privKey, compressed, err := secrets.GetKey(addrs[0])
+	if err != nil {
+		return nil, err
+	}
+	subScript, err := P2WPKHSigScript(witnessProgram, secrets.ChainParams())
+	if err != nil {
+		return nil, err
+	}
+	return txscript.WitnessSignature(tx, sigHashes, idx, amount, subScript,
+		txscript.SigHashAll, privKey, compressed)
+}
+
 // AddAllInputScripts modifies an authored transaction by adding inputs scripts
 // for each input of an authored transaction.  Private keys and redeem scripts
 // are looked up using a SecretsSource based on the previous output script.
@@ -0,0 +1,45 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txauthor
+
+import (
+	"testing"
+
+	"github.com/classzz/czzutil"
+)
+
+// TestSelectCoinsBnBFindsExactMatch asserts that selectCoinsBnB finds an
+// input set whose effective value exactly covers the target when one
+// exists, avoiding the need for a change output.
+func TestSelectCoinsBnBFindsExactMatch(t *testing.T) {
+	const inputFee = czzutil.Amount(100)
+	credits := makeCredits(30100, 50000, 20100)
+
+	selected, ok := selectCoinsBnB(credits, 50000, 0, inputFee)
+	if !ok {
+		t.Fatal("selectCoinsBnB: expected an exact match to be found")
+	}
+
+	var sum czzutil.Amount
+	for _, c := range selected {
+		sum += c.Amount - inputFee
+	}
+	if sum != 50000 {
+		t.Fatalf("selected credits summed to effective value %v, want exactly 50000", sum)
+	}
+}
+
+// TestSelectCoinsBnBNoMatchFallsBack asserts that selectCoinsBnB reports
+// failure, rather than an inexact set, when no subset of credits can exactly
+// cover the target within costOfChange.
+func TestSelectCoinsBnBNoMatchFallsBack(t *testing.T) {
+	const inputFee = czzutil.Amount(0)
+	credits := makeCredits(10000, 10000)
+
+	_, ok := selectCoinsBnB(credits, 50000, 100, inputFee)
+	if ok {
+		t.Fatal("selectCoinsBnB: expected no exact match to be found")
+	}
+}
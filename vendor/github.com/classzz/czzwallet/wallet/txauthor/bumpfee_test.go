@@ -0,0 +1,76 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txauthor
+
+import (
+	"testing"
+
+	"github.com/classzz/classzz/wire"
+	"github.com/classzz/czzutil"
+	"github.com/classzz/czzwallet/wallet/txrules"
+	"github.com/classzz/czzwallet/wallet/txsizes"
+)
+
+// TestBumpFeeTargetsNewFeeRate asserts that BumpFee's replacement fee tracks
+// newFeeRate rather than adding the replacement's fee at newFeeRate on top
+// of the original fee: the paid fee must be within [originalFee, feeAtNewRate]
+// plus the small minimum relay bump floor, never near their sum.
+func TestBumpFeeTargetsNewFeeRate(t *testing.T) {
+	dest := []byte{0x00, 0x14}
+	changeScript := []byte{0x00, 0x14, 0x01}
+	const inputValue = czzutil.Amount(200000)
+	const payValue = czzutil.Amount(100000)
+	const originalChange = czzutil.Amount(99000)
+	const newFeeRate = czzutil.Amount(20000)
+
+	op := wire.OutPoint{Index: 0}
+	original := &AuthoredTx{
+		Tx: &wire.MsgTx{
+			TxIn: []*wire.TxIn{wire.NewTxIn(&op, nil, nil)},
+			TxOut: []*wire.TxOut{
+				wire.NewTxOut(int64(payValue), dest),
+				wire.NewTxOut(int64(originalChange), changeScript),
+			},
+		},
+		PrevScripts:     [][]byte{dest},
+		PrevInputValues: []czzutil.Amount{inputValue},
+		TotalInput:      inputValue,
+		ChangeIndex:     1,
+	}
+
+	noMoreInputs := func(czzutil.Amount) (czzutil.Amount, []*wire.TxIn,
+		[]czzutil.Amount, [][]byte, error) {
+		return 0, nil, nil, nil, nil
+	}
+	noChange := func() ([]byte, error) { return changeScript, nil }
+
+	bumped, err := BumpFee(original, newFeeRate, noMoreInputs, noChange)
+	if err != nil {
+		t.Fatalf("BumpFee: %v", err)
+	}
+
+	var totalOut czzutil.Amount
+	for _, out := range bumped.Tx.TxOut {
+		totalOut += czzutil.Amount(out.Value)
+	}
+	gotFee := bumped.TotalInput - totalOut
+
+	payOutputs := []*wire.TxOut{wire.NewTxOut(int64(payValue), dest)}
+	estimatedSize := txsizes.EstimateSerializeSize(len(bumped.Tx.TxIn), payOutputs, true)
+	feeAtNewRate := txrules.FeeForSerializeSize(newFeeRate, estimatedSize)
+	minRelayBump := txrules.FeeForSerializeSize(minRelayFeePerKb, estimatedSize)
+	originalFee := inputValue - payValue - originalChange
+
+	if gotFee > feeAtNewRate+minRelayBump {
+		t.Fatalf("BumpFee paid %v, more than feeAtNewRate (%v) plus the "+
+			"minimum relay bump (%v); original fee plus feeAtNewRate would "+
+			"have been %v", gotFee, feeAtNewRate, minRelayBump,
+			originalFee+feeAtNewRate)
+	}
+	if gotFee < originalFee {
+		t.Fatalf("BumpFee paid %v, less than the original fee %v",
+			gotFee, originalFee)
+	}
+}
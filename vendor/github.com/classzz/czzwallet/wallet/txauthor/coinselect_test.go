@@ -0,0 +1,79 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txauthor
+
+import (
+	"testing"
+
+	"github.com/classzz/classzz/wire"
+	"github.com/classzz/czzutil"
+)
+
+func makeCredits(amounts ...czzutil.Amount) []Credit {
+	credits := make([]Credit, len(amounts))
+	for i, amt := range amounts {
+		credits[i] = Credit{
+			OutPoint: wire.OutPoint{Index: uint32(i)},
+			PkScript: []byte{0x00, 0x14},
+			Amount:   amt,
+		}
+	}
+	return credits
+}
+
+// TestMakeInputSourceLargestPicksBiggestFirst asserts that
+// CoinSelectionLargest orders credits from largest to smallest amount.
+func TestMakeInputSourceLargestPicksBiggestFirst(t *testing.T) {
+	credits := makeCredits(10000, 50000, 20000)
+	source := MakeInputSource(credits, CoinSelectionLargest)
+
+	total, _, inputValues, _, err := source(1)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if total != 50000 {
+		t.Fatalf("expected first selected credit to be the largest (50000), got total %v", total)
+	}
+	if len(inputValues) != 1 || inputValues[0] != 50000 {
+		t.Fatalf("expected first input value 50000, got %v", inputValues)
+	}
+}
+
+// TestMakeInputSourceSmallestAccumulatesAscending asserts that
+// CoinSelectionSmallest orders credits from smallest to largest amount.
+func TestMakeInputSourceSmallestAccumulatesAscending(t *testing.T) {
+	credits := makeCredits(10000, 50000, 20000)
+	source := MakeInputSource(credits, CoinSelectionSmallest)
+
+	total, _, inputValues, _, err := source(25000)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if total != 30000 {
+		t.Fatalf("expected smallest-first accumulation of 10000+20000=30000, got %v", total)
+	}
+	if len(inputValues) != 2 || inputValues[0] != 10000 || inputValues[1] != 20000 {
+		t.Fatalf("expected input values [10000 20000] in ascending order, got %v", inputValues)
+	}
+}
+
+// TestMakeInputSourceRandomReturnsEnoughValue asserts that CoinSelectionRandom
+// still accumulates enough value to satisfy the target, regardless of order.
+func TestMakeInputSourceRandomReturnsEnoughValue(t *testing.T) {
+	credits := makeCredits(10000, 50000, 20000, 5000)
+	source := MakeInputSource(credits, CoinSelectionRandom)
+
+	total, inputs, inputValues, scripts, err := source(35000)
+	if err != nil {
+		t.Fatalf("source: %v", err)
+	}
+	if total < 35000 {
+		t.Fatalf("expected at least 35000 selected, got %v", total)
+	}
+	if len(inputs) != len(inputValues) || len(inputs) != len(scripts) {
+		t.Fatalf("inputs/inputValues/scripts length mismatch: %d/%d/%d",
+			len(inputs), len(inputValues), len(scripts))
+	}
+}
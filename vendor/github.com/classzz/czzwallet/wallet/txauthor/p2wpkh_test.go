@@ -0,0 +1,57 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txauthor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/classzz/classzz/chaincfg"
+	"github.com/classzz/classzz/txscript"
+	"github.com/classzz/czzutil"
+)
+
+// TestP2WPKHSigScriptIsClassicP2PKH asserts that P2WPKHSigScript derives the
+// classic P2PKH script for a witness program's pubkey hash, rather than
+// returning the witness program itself: BIP-143 signs over the former as
+// scriptCode, and confusing the two was the root cause of a prior bug in
+// this package's P2WPKH signing path.
+func TestP2WPKHSigScriptIsClassicP2PKH(t *testing.T) {
+	params := &chaincfg.MainNetParams
+	var hash160 [20]byte
+	copy(hash160[:], bytes.Repeat([]byte{0xAB}, 20))
+
+	witnessAddr, err := czzutil.NewAddressWitnessPubKeyHash(hash160[:], params)
+	if err != nil {
+		t.Fatalf("NewAddressWitnessPubKeyHash: %v", err)
+	}
+	witnessProgram, err := txscript.PayToAddrScript(witnessAddr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript(witness): %v", err)
+	}
+
+	p2pkhAddr, err := czzutil.NewAddressPubKeyHash(hash160[:], params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	wantSubScript, err := txscript.PayToAddrScript(p2pkhAddr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript(p2pkh): %v", err)
+	}
+
+	gotSubScript, err := P2WPKHSigScript(witnessProgram, params)
+	if err != nil {
+		t.Fatalf("P2WPKHSigScript: %v", err)
+	}
+
+	if !bytes.Equal(gotSubScript, wantSubScript) {
+		t.Fatalf("P2WPKHSigScript = %x, want classic P2PKH script %x",
+			gotSubScript, wantSubScript)
+	}
+	if bytes.Equal(gotSubScript, witnessProgram) {
+		t.Fatal("P2WPKHSigScript returned the witness program itself, " +
+			"not the classic P2PKH script it commits to")
+	}
+}
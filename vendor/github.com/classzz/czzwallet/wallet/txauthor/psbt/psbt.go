@@ -0,0 +1,272 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package psbt provides BIP-174 Partially Signed Bitcoin Transaction
+// authoring and signing support on top of txauthor.AuthoredTx, allowing
+// external signers (hardware wallets, watch-only setups) to participate in
+// the transaction construction flow.
+package psbt
+
+import (
+	"errors"
+
+	"github.com/classzz/classzz/chaincfg"
+	"github.com/classzz/classzz/txscript"
+	"github.com/classzz/classzz/wire"
+	"github.com/classzz/czzutil"
+	czzpsbt "github.com/classzz/czzutil/psbt"
+
+	"github.com/classzz/czzwallet/wallet/txauthor"
+)
+
+// DerivationLookup supplies the BIP-32 key origin (master key fingerprint
+// and derivation path) for the key that redeems a previous output script, so
+// that an external signer such as a hardware wallet knows which of its keys
+// to sign with. A nil result with a nil error means no hint is available for
+// that script and New leaves the input's derivation field empty.
+type DerivationLookup func(pkScript []byte) (*czzpsbt.Bip32Derivation, error)
+
+// New converts an AuthoredTx into an unsigned PSBT packet, populating each
+// input's WitnessUtxo field with its previous output plus a BIP-32
+// derivation hint from lookupDerivation, so that an external signer has
+// everything it needs to produce a signature without further lookups.
+// lookupDerivation may be nil if no derivation hints are available.
+//
+// BUGS: AuthoredTx only records each input's previous output script and
+// value, not its full previous transaction, so New can only ever populate
+// WitnessUtxo. BIP-174 recommends NonWitnessUtxo (the full previous
+// transaction) for non-segwit inputs so a signer can verify the claimed
+// value instead of trusting it; signers that enforce this for legacy inputs
+// will need another source for it.
+func New(authored *txauthor.AuthoredTx, lookupDerivation DerivationLookup) (*czzpsbt.Packet, error) {
+	packet, err := czzpsbt.NewFromUnsignedTx(authored.Tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(authored.Tx.TxIn) != len(authored.PrevScripts) ||
+		len(authored.Tx.TxIn) != len(authored.PrevInputValues) {
+		return nil, errors.New("authored transaction inputs and " +
+			"prevout metadata slices must have equal length")
+	}
+
+	for i, pkScript := range authored.PrevScripts {
+		in := &packet.Inputs[i]
+		in.WitnessUtxo = wire.NewTxOut(int64(authored.PrevInputValues[i]), pkScript)
+		in.SighashType = txscript.SigHashAll
+
+		if lookupDerivation == nil {
+			continue
+		}
+		deriv, err := lookupDerivation(pkScript)
+		if err != nil {
+			return nil, err
+		}
+		if deriv != nil {
+			in.Bip32Derivation = append(in.Bip32Derivation, deriv)
+		}
+	}
+
+	return packet, nil
+}
+
+// AuthoredTx converts a (possibly finalized) PSBT packet back into an
+// AuthoredTx, recovering PrevScripts and PrevInputValues from each input's
+// WitnessUtxo or NonWitnessUtxo.
+func AuthoredTx(packet *czzpsbt.Packet) (*txauthor.AuthoredTx, error) {
+	prevScripts := make([][]byte, len(packet.Inputs))
+	prevInputValues := make([]czzutil.Amount, len(packet.Inputs))
+	var totalInput czzutil.Amount
+
+	for i, in := range packet.Inputs {
+		switch {
+		case in.WitnessUtxo != nil:
+			prevScripts[i] = in.WitnessUtxo.PkScript
+			prevInputValues[i] = czzutil.Amount(in.WitnessUtxo.Value)
+		case in.NonWitnessUtxo != nil:
+			outIdx := packet.UnsignedTx.TxIn[i].PreviousOutPoint.Index
+			prevOut := in.NonWitnessUtxo.TxOut[outIdx]
+			prevScripts[i] = prevOut.PkScript
+			prevInputValues[i] = czzutil.Amount(prevOut.Value)
+		default:
+			return nil, errors.New("psbt input is missing prevout information")
+		}
+		totalInput += prevInputValues[i]
+	}
+
+	return &txauthor.AuthoredTx{
+		Tx:              packet.UnsignedTx,
+		PrevScripts:     prevScripts,
+		PrevInputValues: prevInputValues,
+		TotalInput:      totalInput,
+		ChangeIndex:     -1,
+	}, nil
+}
+
+// SignPSBT fills in a partial signature for every input of packet that
+// secrets can produce a key for, finalizing each such input in place. Inputs
+// belonging to other signers are left untouched so that multiple parties can
+// sign the same packet independently, as required by BIP-174. The signature
+// produced for each input matches its previous output script type, following
+// the same P2WPKH/P2SH-P2WPKH/legacy dispatch as AddAllInputScripts.
+func SignPSBT(packet *czzpsbt.Packet, secrets txauthor.SecretsSource) error {
+	chainParams := secrets.ChainParams()
+
+	prevPkScripts := make([][]byte, len(packet.Inputs))
+	prevValues := make([]czzutil.Amount, len(packet.Inputs))
+	for i, in := range packet.Inputs {
+		switch {
+		case in.WitnessUtxo != nil:
+			prevPkScripts[i] = in.WitnessUtxo.PkScript
+			prevValues[i] = czzutil.Amount(in.WitnessUtxo.Value)
+		case in.NonWitnessUtxo != nil:
+			outIdx := packet.UnsignedTx.TxIn[i].PreviousOutPoint.Index
+			prevOut := in.NonWitnessUtxo.TxOut[outIdx]
+			prevPkScripts[i] = prevOut.PkScript
+			prevValues[i] = czzutil.Amount(prevOut.Value)
+		default:
+			return errors.New("psbt input is missing prevout information")
+		}
+	}
+
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx)
+
+	for i := range packet.Inputs {
+		in := &packet.Inputs[i]
+		pkScript := prevPkScripts[i]
+		amount := int64(prevValues[i].ToUnit(czzutil.AmountSatoshi))
+
+		var signed bool
+		var err error
+		switch {
+		case txscript.IsWitnessProgram(pkScript) && !txscript.IsPayToWitnessPubKeyHash(pkScript):
+			return errors.New("unsupported witness program: only P2WPKH " +
+				"witness inputs are supported")
+
+		case txscript.IsPayToWitnessPubKeyHash(pkScript):
+			signed, err = signWitnessInput(packet.UnsignedTx, sigHashes, i,
+				amount, pkScript, chainParams, secrets, in)
+
+		case txscript.IsPayToScriptHash(pkScript):
+			var addrs []czzutil.Address
+			_, addrs, _, err = txscript.ExtractPkScriptAddrs(pkScript, chainParams)
+			if err != nil {
+				return err
+			}
+			var redeemScript []byte
+			redeemScript, err = secrets.GetScript(addrs[0])
+			if err != nil {
+				// No redeem script available from this signer.
+				continue
+			}
+			in.RedeemScript = redeemScript
+			if txscript.IsPayToWitnessPubKeyHash(redeemScript) {
+				signed, err = signWitnessInput(packet.UnsignedTx, sigHashes, i,
+					amount, redeemScript, chainParams, secrets, in)
+			} else {
+				signed, err = signLegacyInput(packet.UnsignedTx, i,
+					pkScript, chainParams, secrets, in)
+			}
+
+		default:
+			signed, err = signLegacyInput(packet.UnsignedTx, i,
+				pkScript, chainParams, secrets, in)
+		}
+		if err != nil {
+			return err
+		}
+		if !signed {
+			// This signer has no key for this input; leave it for
+			// another party to sign.
+			continue
+		}
+
+		if err := czzpsbt.Finalize(packet, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signWitnessInput produces a BIP-143 partial signature for a native or
+// nested P2WPKH input, where witnessProgram is the inner witness program
+// (pkScript itself for a native input, the redeem script for a nested one).
+func signWitnessInput(tx *wire.MsgTx, sigHashes *txscript.TxSigHashes, idx int, amount int64,
+	witnessProgram []byte, chainParams *chaincfg.Params, secrets txauthor.SecretsSource,
+	in *czzpsbt.PInput) (bool, error) {
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(witnessProgram, chainParams)
+	if err != nil {
+		return false, err
+	}
+	privKey, compressed, err := secrets.GetKey(addrs[0])
+	if err != nil {
+		return false, nil
+	}
+	subScript, err := txauthor.P2WPKHSigScript(witnessProgram, chainParams)
+	if err != nil {
+		return false, err
+	}
+
+	sigHashType := in.SighashType
+	if sigHashType == 0 {
+		sigHashType = txscript.SigHashAll
+	}
+	sig, err := txscript.RawTxInWitnessSignature(tx, sigHashes, idx, amount,
+		subScript, sigHashType, privKey)
+	if err != nil {
+		return false, err
+	}
+
+	pubKey := privKey.PubKey()
+	var pubKeyBytes []byte
+	if compressed {
+		pubKeyBytes = pubKey.SerializeCompressed()
+	} else {
+		pubKeyBytes = pubKey.SerializeUncompressed()
+	}
+	in.PartialSigs = append(in.PartialSigs, &czzpsbt.PartialSig{
+		PubKey:    pubKeyBytes,
+		Signature: sig,
+	})
+	return true, nil
+}
+
+// signLegacyInput produces a classic ECDSA partial signature for a P2PKH or
+// non-segwit P2SH input.
+func signLegacyInput(tx *wire.MsgTx, idx int, pkScript []byte, chainParams *chaincfg.Params,
+	secrets txauthor.SecretsSource, in *czzpsbt.PInput) (bool, error) {
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, chainParams)
+	if err != nil {
+		return false, err
+	}
+	privKey, compressed, err := secrets.GetKey(addrs[0])
+	if err != nil {
+		return false, nil
+	}
+
+	sigHashType := in.SighashType
+	if sigHashType == 0 {
+		sigHashType = txscript.SigHashAll
+	}
+	sig, err := txscript.RawTxInSignature(tx, idx, pkScript, sigHashType, privKey)
+	if err != nil {
+		return false, err
+	}
+
+	pubKey := privKey.PubKey()
+	var pubKeyBytes []byte
+	if compressed {
+		pubKeyBytes = pubKey.SerializeCompressed()
+	} else {
+		pubKeyBytes = pubKey.SerializeUncompressed()
+	}
+	in.PartialSigs = append(in.PartialSigs, &czzpsbt.PartialSig{
+		PubKey:    pubKeyBytes,
+		Signature: sig,
+	})
+	return true, nil
+}
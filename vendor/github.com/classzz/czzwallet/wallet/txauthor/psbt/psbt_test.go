@@ -0,0 +1,69 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/classzz/classzz/wire"
+	"github.com/classzz/czzutil"
+	czzpsbt "github.com/classzz/czzutil/psbt"
+
+	"github.com/classzz/czzwallet/wallet/txauthor"
+)
+
+// TestNewAndAuthoredTxRoundTrip asserts that converting an AuthoredTx to a
+// PSBT packet with New and back with AuthoredTx recovers the same previous
+// output scripts and values, and that a supplied derivation hint is recorded
+// on the matching input.
+func TestNewAndAuthoredTxRoundTrip(t *testing.T) {
+	dest := []byte{0x00, 0x14}
+	op := wire.OutPoint{Index: 0}
+	authored := &txauthor.AuthoredTx{
+		Tx: &wire.MsgTx{
+			Version: wire.TxVersion,
+			TxIn:    []*wire.TxIn{wire.NewTxIn(&op, nil, nil)},
+			TxOut:   []*wire.TxOut{wire.NewTxOut(90000, dest)},
+		},
+		PrevScripts:     [][]byte{dest},
+		PrevInputValues: []czzutil.Amount{100000},
+		TotalInput:      100000,
+		ChangeIndex:     -1,
+	}
+
+	deriv := &czzpsbt.Bip32Derivation{PubKey: []byte{0x02}}
+	lookup := func(pkScript []byte) (*czzpsbt.Bip32Derivation, error) {
+		if bytes.Equal(pkScript, dest) {
+			return deriv, nil
+		}
+		return nil, nil
+	}
+
+	packet, err := New(authored, lookup)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if len(packet.Inputs) != 1 {
+		t.Fatalf("expected 1 packet input, got %d", len(packet.Inputs))
+	}
+	if len(packet.Inputs[0].Bip32Derivation) != 1 || packet.Inputs[0].Bip32Derivation[0] != deriv {
+		t.Fatalf("expected the looked-up derivation hint to be recorded on input 0")
+	}
+
+	roundTripped, err := AuthoredTx(packet)
+	if err != nil {
+		t.Fatalf("AuthoredTx: %v", err)
+	}
+	if roundTripped.TotalInput != authored.TotalInput {
+		t.Fatalf("TotalInput = %v, want %v", roundTripped.TotalInput, authored.TotalInput)
+	}
+	if len(roundTripped.PrevScripts) != 1 || !bytes.Equal(roundTripped.PrevScripts[0], dest) {
+		t.Fatalf("PrevScripts = %v, want [%v]", roundTripped.PrevScripts, dest)
+	}
+	if len(roundTripped.PrevInputValues) != 1 || roundTripped.PrevInputValues[0] != 100000 {
+		t.Fatalf("PrevInputValues = %v, want [100000]", roundTripped.PrevInputValues)
+	}
+}
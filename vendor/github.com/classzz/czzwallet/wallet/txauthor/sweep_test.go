@@ -0,0 +1,72 @@
+// Copyright (c) 2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txauthor
+
+import (
+	"testing"
+
+	"github.com/classzz/classzz/wire"
+	"github.com/classzz/czzutil"
+	"github.com/classzz/czzwallet/wallet/txrules"
+	"github.com/classzz/czzwallet/wallet/txsizes"
+)
+
+// TestNewUnsignedSweepTransactionPaysFeeOnce asserts that the value swept
+// out of the inputs equals the computed fee exactly once: total input minus
+// total output must equal the fee, regardless of whether feeSourceIndex
+// designates a single output or the excess is split proportionally across
+// all of them.
+func TestNewUnsignedSweepTransactionPaysFeeOnce(t *testing.T) {
+	dest := []byte{0x00, 0x14}
+	const inputValue = czzutil.Amount(100000)
+	const relayFeePerKb = czzutil.Amount(10000)
+
+	fetch := func() ([]*wire.TxIn, []czzutil.Amount, [][]byte, error) {
+		op := wire.OutPoint{Index: 0}
+		return []*wire.TxIn{wire.NewTxIn(&op, nil, nil)},
+			[]czzutil.Amount{inputValue},
+			[][]byte{dest},
+			nil
+	}
+
+	tests := []struct {
+		name           string
+		outputs        []*wire.TxOut
+		feeSourceIndex int
+	}{
+		{"single output, proportional", []*wire.TxOut{wire.NewTxOut(90000, dest)}, -1},
+		{"two outputs, designated fee source", []*wire.TxOut{
+			wire.NewTxOut(40000, dest),
+			wire.NewTxOut(50000, dest),
+		}, 0},
+		{"two outputs, proportional", []*wire.TxOut{
+			wire.NewTxOut(40000, dest),
+			wire.NewTxOut(50000, dest),
+		}, -1},
+	}
+
+	for _, test := range tests {
+		authored, err := NewUnsignedSweepTransaction(test.outputs, relayFeePerKb,
+			test.feeSourceIndex, fetch)
+		if err != nil {
+			t.Errorf("%s: NewUnsignedSweepTransaction: %v", test.name, err)
+			continue
+		}
+
+		estimatedSize := txsizes.EstimateSerializeSize(len(authored.Tx.TxIn),
+			test.outputs, false)
+		wantFee := txrules.FeeForSerializeSize(relayFeePerKb, estimatedSize)
+
+		var totalOut czzutil.Amount
+		for _, out := range authored.Tx.TxOut {
+			totalOut += czzutil.Amount(out.Value)
+		}
+		gotFee := authored.TotalInput - totalOut
+		if gotFee != wantFee {
+			t.Errorf("%s: swept transaction paid fee %v, want %v",
+				test.name, gotFee, wantFee)
+		}
+	}
+}